@@ -0,0 +1,103 @@
+// Package css compiles W3C CSS selector strings into matchers
+// that can be used to query trees of html.Nodes.
+//
+// It is built on top of [htree.Walk] and [htree.FindEl]
+// and is meant as a more ergonomic alternative to hand-written
+// `func(*html.Node) bool` predicates
+// for the common case of selecting nodes the way a browser's
+// `querySelector`/`querySelectorAll` would.
+//
+// A namespace-qualified attribute selector such as `[xlink:href]`
+// is accepted, but since html.Node attributes carry no namespace
+// information for this package to match against, the namespace
+// prefix is discarded and the selector matches by local name alone.
+package css
+
+import (
+	"fmt"
+
+	"github.com/bobg/htree"
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled CSS selector.
+// A Selector may contain multiple comma-separated selectors,
+// any one of which matching a node counts as a match.
+type Selector struct {
+	lists [][]compoundSelector
+}
+
+// compoundSelector is one step of a selector
+// (e.g. `div.foo[bar]`),
+// together with the combinator that relates it
+// to the step before it in the selector list
+// (e.g. the `>` in `ul > li.foo`).
+// combinator is zero for the first step in a list.
+type compoundSelector struct {
+	combinator byte // 0, ' ', '>', '+', or '~'
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrTest
+	pseudos    []pseudoTest
+}
+
+type attrTest struct {
+	key, val, op string
+}
+
+type pseudoTest struct {
+	name string
+	a, b int // for nth-child(an+b)
+	not  *Selector
+}
+
+// Compile parses a CSS selector string and returns the compiled form.
+func Compile(selector string) (*Selector, error) {
+	p := &parser{s: selector}
+	sel, err := p.parseSelectorList()
+	if err != nil {
+		return nil, fmt.Errorf("parsing selector %q: %w", selector, err)
+	}
+	return sel, nil
+}
+
+// Match tells whether n satisfies the selector.
+func (s *Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, list := range s.lists {
+		if matchList(n, list) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuerySelector finds the first node in a depth-first search of root
+// that matches selector.
+func QuerySelector(root *html.Node, selector string) (*html.Node, error) {
+	sel, err := Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return htree.FindEl(root, sel.Match), nil
+}
+
+// QuerySelectorAll produces an iterator over the nodes in root
+// that match selector,
+// in document order.
+func QuerySelectorAll(root *html.Node, selector string) (htree.Seq, error) {
+	sel, err := Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(*html.Node) bool) {
+		for n := range htree.Walk(root) {
+			if sel.Match(n) && !yield(n) {
+				return
+			}
+		}
+	}, nil
+}
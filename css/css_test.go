@@ -0,0 +1,106 @@
+package css
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bobg/htree"
+	"golang.org/x/net/html"
+)
+
+func TestQuerySelector(t *testing.T) {
+	const doc = `
+<div id="main" class="wrapper">
+  <ul class="list">
+    <li class="item first">a</li>
+    <li class="item" data-x="foo">b</li>
+    <li class="item last">c</li>
+  </ul>
+  <p>hello <a href="https://example.com">link</a></p>
+  <svg><use xlink:href="#icon"></use></svg>
+</div>
+`
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		sel, want string
+	}{
+		{"#main", "wrapper"},
+		{".wrapper", "wrapper"},
+		{"ul > li.first", "item first"},
+		{"li:nth-child(2)", "item"},
+		{"li:last-child", "item last"},
+		{"div > ul + p", ""},
+		{"a[href^=\"https://\"]", "link"},
+		{"li:not(.first)", "item"},
+		{"use[xlink:href]", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.sel, func(t *testing.T) {
+			n, err := QuerySelector(root, c.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.want == "" {
+				if n == nil {
+					t.Fatal("no match")
+				}
+				return
+			}
+			if n == nil {
+				t.Fatalf("no match for %q", c.sel)
+			}
+			got := htree.ElAttr(n, "class")
+			if got == "" {
+				got, _ = htree.Text(n)
+			}
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestQuerySelectorAll(t *testing.T) {
+	const doc = `<ul><li>a</li><li>b</li><li>c</li></ul>`
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seq, err := QuerySelectorAll(root, "li")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for n := range seq {
+		s, err := htree.Text(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, sel := range []string{"", "div >", "[attr", ":bogus"} {
+		if _, err := Compile(sel); err == nil {
+			t.Errorf("Compile(%q) succeeded, want error", sel)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package css
+
+import (
+	"strings"
+
+	"github.com/bobg/htree"
+	"golang.org/x/net/html"
+)
+
+func matchList(n *html.Node, list []compoundSelector) bool {
+	if len(list) == 0 {
+		return true
+	}
+	last := list[len(list)-1]
+	if !matchCompound(n, last) {
+		return false
+	}
+	if len(list) == 1 {
+		return true
+	}
+	rest := list[:len(list)-1]
+	switch last.combinator {
+	case '>':
+		return n.Parent != nil && matchList(n.Parent, rest)
+
+	case '+':
+		if prev := prevElementSibling(n); prev != nil {
+			return matchList(prev, rest)
+		}
+		return false
+
+	case '~':
+		for prev := prevElementSibling(n); prev != nil; prev = prevElementSibling(prev) {
+			if matchList(prev, rest) {
+				return true
+			}
+		}
+		return false
+
+	default: // descendant combinator (plain whitespace)
+		for p := n.Parent; p != nil; p = p.Parent {
+			if matchList(p, rest) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchCompound(n *html.Node, cs compoundSelector) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if cs.tag != "" && n.Data != cs.tag {
+		return false
+	}
+	if cs.id != "" && htree.ElAttr(n, "id") != cs.id {
+		return false
+	}
+	for _, c := range cs.classes {
+		if !htree.ElClassContains(n, c) {
+			return false
+		}
+	}
+	for _, at := range cs.attrs {
+		if !matchAttr(n, at) {
+			return false
+		}
+	}
+	for _, pt := range cs.pseudos {
+		if !matchPseudo(n, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAttr(n *html.Node, at attrTest) bool {
+	val, ok := attrValue(n, at.key)
+	if !ok {
+		return false
+	}
+	switch at.op {
+	case "":
+		return true
+	case "=":
+		return val == at.val
+	case "~=":
+		for _, f := range strings.Fields(val) {
+			if f == at.val {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return val == at.val || strings.HasPrefix(val, at.val+"-")
+	case "^=":
+		return strings.HasPrefix(val, at.val)
+	case "$=":
+		return strings.HasSuffix(val, at.val)
+	case "*=":
+		return strings.Contains(val, at.val)
+	default:
+		return false
+	}
+}
+
+func matchPseudo(n *html.Node, pt pseudoTest) bool {
+	switch pt.name {
+	case "first-child":
+		return prevElementSibling(n) == nil
+	case "last-child":
+		return nextElementSibling(n) == nil
+	case "only-child":
+		return prevElementSibling(n) == nil && nextElementSibling(n) == nil
+	case "nth-child":
+		return matchNth(elementIndex(n), pt.a, pt.b)
+	case "not":
+		return pt.not != nil && !pt.not.Match(n)
+	default:
+		return false
+	}
+}
+
+// matchNth tells whether idx (a 1-based position) satisfies the
+// an+b formula described by a and b.
+func matchNth(idx, a, b int) bool {
+	diff := idx - b
+	if a == 0 {
+		return diff == 0
+	}
+	if diff%a != 0 {
+		return false
+	}
+	return diff/a >= 0
+}
+
+// attrValue returns n's value for the attribute key,
+// matching the attribute name case-insensitively as HTML requires.
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func prevElementSibling(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextElementSibling(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+// elementIndex returns n's 1-based position among its element siblings.
+func elementIndex(n *html.Node) int {
+	idx := 1
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			idx++
+		}
+	}
+	return idx
+}
@@ -0,0 +1,361 @@
+package css
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser turns a selector string into a list of compoundSelector chains,
+// one per comma-separated alternative.
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) peek() byte {
+	if p.i >= len(p.s) {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && isSpace(p.s[p.i]) {
+		p.i++
+	}
+}
+
+func (p *parser) parseSelectorList() (*Selector, error) {
+	var lists [][]compoundSelector
+	for {
+		p.skipSpace()
+		chain, err := p.parseChain()
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, chain)
+		p.skipSpace()
+		if p.peek() != ',' {
+			break
+		}
+		p.i++
+	}
+	p.skipSpace()
+	if p.i != len(p.s) {
+		return nil, fmt.Errorf("unexpected character %q at position %d", p.peek(), p.i)
+	}
+	return &Selector{lists: lists}, nil
+}
+
+func (p *parser) parseChain() ([]compoundSelector, error) {
+	first, err := p.parseCompound(0)
+	if err != nil {
+		return nil, err
+	}
+	chain := []compoundSelector{first}
+
+	for {
+		hadSpace := false
+		for p.i < len(p.s) && isSpace(p.s[p.i]) {
+			p.i++
+			hadSpace = true
+		}
+		if p.i >= len(p.s) {
+			break
+		}
+
+		var comb byte
+		switch p.peek() {
+		case ',':
+			return chain, nil
+		case '>', '+', '~':
+			comb = p.peek()
+			p.i++
+			p.skipSpace()
+		default:
+			if !hadSpace {
+				return nil, fmt.Errorf("unexpected character %q at position %d", p.peek(), p.i)
+			}
+			comb = ' '
+		}
+
+		cs, err := p.parseCompound(comb)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cs)
+	}
+
+	return chain, nil
+}
+
+func (p *parser) parseCompound(comb byte) (compoundSelector, error) {
+	cs := compoundSelector{combinator: comb}
+
+	switch {
+	case p.peek() == '*':
+		p.i++
+	case isIdentStart(p.peek()):
+		cs.tag = strings.ToLower(p.parseIdent())
+	}
+
+loop:
+	for p.i < len(p.s) {
+		switch p.peek() {
+		case '#':
+			p.i++
+			cs.id = p.parseIdent()
+		case '.':
+			p.i++
+			cs.classes = append(cs.classes, p.parseIdent())
+		case '[':
+			p.i++
+			at, err := p.parseAttr()
+			if err != nil {
+				return cs, err
+			}
+			cs.attrs = append(cs.attrs, at)
+		case ':':
+			p.i++
+			pt, err := p.parsePseudo()
+			if err != nil {
+				return cs, err
+			}
+			cs.pseudos = append(cs.pseudos, pt)
+		default:
+			break loop
+		}
+	}
+
+	if cs.tag == "" && cs.id == "" && len(cs.classes) == 0 && len(cs.attrs) == 0 && len(cs.pseudos) == 0 {
+		return cs, fmt.Errorf("expected a selector at position %d", p.i)
+	}
+
+	return cs, nil
+}
+
+// parseIdent reads a CSS identifier, honoring backslash escapes.
+func (p *parser) parseIdent() string {
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if c == '\\' && p.i+1 < len(p.s) {
+			b.WriteByte(p.s[p.i+1])
+			p.i += 2
+			continue
+		}
+		if !isIdentChar(c) {
+			break
+		}
+		b.WriteByte(c)
+		p.i++
+	}
+	return b.String()
+}
+
+func (p *parser) parseValue() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		quote := c
+		p.i++
+		var b strings.Builder
+		for p.i < len(p.s) && p.s[p.i] != quote {
+			if p.s[p.i] == '\\' && p.i+1 < len(p.s) {
+				b.WriteByte(p.s[p.i+1])
+				p.i += 2
+				continue
+			}
+			b.WriteByte(p.s[p.i])
+			p.i++
+		}
+		if p.i >= len(p.s) {
+			return "", fmt.Errorf("unterminated string starting at position %d", p.i)
+		}
+		p.i++ // closing quote
+		return b.String(), nil
+	}
+	return p.parseIdent(), nil
+}
+
+// parseAttr parses the inside of an `[attr...]` selector,
+// having already consumed the opening `[`.
+//
+// A namespace-qualified name such as `xlink:href` is accepted;
+// since html.Node attributes carry no namespace information that
+// ElAttr and attrValue can query, the prefix is discarded and the
+// selector matches by local name alone, the same way a plain
+// `[href]` would.
+func (p *parser) parseAttr() (attrTest, error) {
+	p.skipSpace()
+	key := p.parseIdent()
+	if key == "" {
+		return attrTest{}, fmt.Errorf("expected attribute name at position %d", p.i)
+	}
+	if p.peek() == ':' && p.i+1 < len(p.s) && isIdentStart(p.s[p.i+1]) {
+		p.i++
+		local := p.parseIdent()
+		key = local
+	}
+	p.skipSpace()
+
+	if p.peek() == ']' {
+		p.i++
+		return attrTest{key: key}, nil
+	}
+
+	var op string
+	switch p.peek() {
+	case '=':
+		op = "="
+		p.i++
+	case '~', '|', '^', '$', '*':
+		if p.i+1 < len(p.s) && p.s[p.i+1] == '=' {
+			op = p.s[p.i : p.i+2]
+			p.i += 2
+		}
+	}
+	if op == "" {
+		return attrTest{}, fmt.Errorf("expected attribute operator at position %d", p.i)
+	}
+
+	p.skipSpace()
+	val, err := p.parseValue()
+	if err != nil {
+		return attrTest{}, err
+	}
+	p.skipSpace()
+
+	// Optional case-sensitivity flag ('i'/'I' or 's'/'S'); we always
+	// match attribute values case-sensitively, so it is only consumed here.
+	if c := p.peek(); c == 'i' || c == 'I' || c == 's' || c == 'S' {
+		p.i++
+		p.skipSpace()
+	}
+
+	if p.peek() != ']' {
+		return attrTest{}, fmt.Errorf("expected ']' at position %d", p.i)
+	}
+	p.i++
+
+	return attrTest{key: key, op: op, val: val}, nil
+}
+
+// parsePseudo parses a pseudo-class,
+// having already consumed the leading `:`.
+func (p *parser) parsePseudo() (pseudoTest, error) {
+	name := strings.ToLower(p.parseIdent())
+	if name == "" {
+		return pseudoTest{}, fmt.Errorf("expected pseudo-class name at position %d", p.i)
+	}
+
+	if p.peek() != '(' {
+		switch name {
+		case "first-child", "last-child", "only-child":
+			return pseudoTest{name: name}, nil
+		default:
+			return pseudoTest{}, fmt.Errorf("unsupported pseudo-class %q", name)
+		}
+	}
+	p.i++ // '('
+	p.skipSpace()
+
+	switch name {
+	case "nth-child":
+		start := p.i
+		for p.i < len(p.s) && p.s[p.i] != ')' {
+			p.i++
+		}
+		if p.peek() != ')' {
+			return pseudoTest{}, fmt.Errorf("unterminated nth-child()")
+		}
+		arg := strings.TrimSpace(p.s[start:p.i])
+		p.i++
+		a, b, err := parseNth(arg)
+		if err != nil {
+			return pseudoTest{}, err
+		}
+		return pseudoTest{name: name, a: a, b: b}, nil
+
+	case "not":
+		start := p.i
+		depth := 1
+		for p.i < len(p.s) && depth > 0 {
+			switch p.s[p.i] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+			p.i++
+		}
+		if p.peek() != ')' {
+			return pseudoTest{}, fmt.Errorf("unterminated :not()")
+		}
+		inner, err := Compile(p.s[start:p.i])
+		p.i++
+		if err != nil {
+			return pseudoTest{}, err
+		}
+		return pseudoTest{name: name, not: inner}, nil
+
+	default:
+		return pseudoTest{}, fmt.Errorf("unsupported pseudo-class %q", name)
+	}
+}
+
+// parseNth parses the argument of an :nth-child() pseudo-class,
+// an expression of the form "odd", "even", "<a>n+<b>", or a bare integer.
+func parseNth(s string) (a, b int, err error) {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	switch s {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+
+	idx := strings.IndexByte(s, 'n')
+	if idx < 0 {
+		b, err = strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth-child argument %q", s)
+		}
+		return 0, b, nil
+	}
+
+	switch aPart := s[:idx]; aPart {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		a, err = strconv.Atoi(aPart)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth-child argument %q", s)
+		}
+	}
+
+	if bPart := s[idx+1:]; bPart != "" {
+		b, err = strconv.Atoi(bPart)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth-child argument %q", s)
+		}
+	}
+
+	return a, b, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '\\' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c == '-' || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,498 @@
+package htree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// LinkStyle controls how [WriteMarkdown] renders `<a>` elements.
+type LinkStyle int
+
+const (
+	// LinkInline renders links as `[text](href)`.
+	LinkInline LinkStyle = iota
+
+	// LinkReference renders links as `[text][n]`,
+	// with the `href`s collected into a block
+	// of `[n]: href` definitions at the end of the output.
+	LinkReference
+)
+
+// MarkdownOptions controls the behavior of [WriteMarkdown].
+// The zero value renders inline links with a backtick fence character.
+type MarkdownOptions struct {
+	// BaseURL, if non-empty, is used to resolve relative `href`s
+	// found on `<a>` elements into absolute URLs.
+	BaseURL string
+
+	// FenceChar is the character used to fence code blocks
+	// produced from `<pre><code>`.
+	// The zero value means '`'.
+	FenceChar byte
+
+	// LinkStyle selects how `<a>` elements are rendered.
+	LinkStyle LinkStyle
+
+	// RawHTML, if true, renders elements this package does not
+	// otherwise understand (e.g. `<svg>`, `<video>`) as raw HTML
+	// instead of just recursing into their text content.
+	RawHTML bool
+}
+
+// WriteMarkdown converts the HTML subtree rooted at node to
+// GitHub-flavored Markdown and writes it to w.
+func WriteMarkdown(w io.Writer, node *html.Node, opts *MarkdownOptions) error {
+	o := MarkdownOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.FenceChar == 0 {
+		o.FenceChar = '`'
+	}
+
+	mw := &markdownWriter{opts: o}
+	if err := mw.block(node); err != nil {
+		return err
+	}
+	mw.flushParagraph()
+
+	out := strings.Join(mw.blocks, "\n\n")
+	if o.LinkStyle == LinkReference && len(mw.refs) > 0 {
+		refLines := make([]string, len(mw.refs))
+		for i, ref := range mw.refs {
+			refLines[i] = fmt.Sprintf("[%d]: %s", i+1, ref)
+		}
+		if out != "" {
+			out += "\n\n"
+		}
+		out += strings.Join(refLines, "\n")
+	}
+
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// Markdown is a convenience wrapper for [WriteMarkdown]
+// using the default [MarkdownOptions],
+// returning the result as a string.
+func Markdown(node *html.Node) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := WriteMarkdown(buf, node, nil); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// markdownWriter accumulates a sequence of Markdown blocks
+// (paragraphs, headings, lists, and so on),
+// which are joined with blank lines by [WriteMarkdown].
+type markdownWriter struct {
+	opts   MarkdownOptions
+	blocks []string
+	para   strings.Builder
+	refs   []string
+}
+
+func (mw *markdownWriter) flushParagraph() {
+	if s := strings.TrimSpace(mw.para.String()); s != "" {
+		mw.blocks = append(mw.blocks, s)
+	}
+	mw.para.Reset()
+}
+
+func (mw *markdownWriter) block(n *html.Node) error {
+	switch n.Type {
+	case html.DocumentNode:
+		return mw.children(n)
+
+	case html.TextNode:
+		mw.para.WriteString(escapeMarkdown(html.UnescapeString(n.Data), mw.para.Len() == 0))
+		return nil
+
+	case html.ElementNode:
+		return mw.element(n)
+
+	default: // CommentNode, DoctypeNode, RawNode, ErrorNode
+		return nil
+	}
+}
+
+func (mw *markdownWriter) children(n *html.Node) error {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := mw.block(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mw *markdownWriter) element(n *html.Node) error {
+	switch n.DataAtom {
+	case atom.Script, atom.Style:
+		return nil
+
+	case atom.Br:
+		mw.para.WriteString("  \n")
+		return nil
+
+	case atom.Hr:
+		mw.flushParagraph()
+		mw.blocks = append(mw.blocks, "---")
+		return nil
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		mw.flushParagraph()
+		level := int(n.DataAtom-atom.H1) + 1
+		inline, err := mw.inlineChildren(n)
+		if err != nil {
+			return err
+		}
+		mw.blocks = append(mw.blocks, strings.Repeat("#", level)+" "+strings.TrimSpace(inline))
+		return nil
+
+	case atom.Strong, atom.B:
+		inline, err := mw.inlineChildren(n)
+		if err != nil {
+			return err
+		}
+		mw.para.WriteString("**" + inline + "**")
+		return nil
+
+	case atom.Em, atom.I:
+		inline, err := mw.inlineChildren(n)
+		if err != nil {
+			return err
+		}
+		mw.para.WriteString("*" + inline + "*")
+		return nil
+
+	case atom.Code:
+		text, err := Text(n)
+		if err != nil {
+			return err
+		}
+		mw.para.WriteString("`" + text + "`")
+		return nil
+
+	case atom.A:
+		return mw.link(n)
+
+	case atom.Ul, atom.Ol:
+		mw.flushParagraph()
+		block, err := mw.list(n)
+		if err != nil {
+			return err
+		}
+		mw.blocks = append(mw.blocks, block)
+		return nil
+
+	case atom.Blockquote:
+		mw.flushParagraph()
+		sub := &markdownWriter{opts: mw.opts}
+		if err := sub.children(n); err != nil {
+			return err
+		}
+		sub.flushParagraph()
+		mw.refs = append(mw.refs, sub.refs...)
+
+		quoted := make([]string, len(sub.blocks))
+		for i, b := range sub.blocks {
+			quoted[i] = prefixLines(b, "> ")
+		}
+		mw.blocks = append(mw.blocks, strings.Join(quoted, "\n>\n"))
+		return nil
+
+	case atom.Pre:
+		mw.flushParagraph()
+		block, err := mw.codeBlock(n)
+		if err != nil {
+			return err
+		}
+		mw.blocks = append(mw.blocks, block)
+		return nil
+
+	case atom.Table:
+		mw.flushParagraph()
+		block, err := mw.table(n)
+		if err != nil {
+			return err
+		}
+		mw.blocks = append(mw.blocks, block)
+		return nil
+
+	default:
+		if voidElements.Has(n.DataAtom) {
+			return nil
+		}
+		if mw.opts.RawHTML && !blockElements.Has(n.DataAtom) && !isKnownInline(n.DataAtom) {
+			var buf bytes.Buffer
+			if err := html.Render(&buf, n); err != nil {
+				return err
+			}
+			mw.para.WriteString(buf.String())
+			return nil
+		}
+		isBlock := blockElements.Has(n.DataAtom)
+		if isBlock {
+			mw.flushParagraph()
+		}
+		if err := mw.children(n); err != nil {
+			return err
+		}
+		if isBlock {
+			mw.flushParagraph()
+		}
+		return nil
+	}
+}
+
+func isKnownInline(a atom.Atom) bool {
+	switch a {
+	case atom.Strong, atom.B, atom.Em, atom.I, atom.Code, atom.A, atom.Br, atom.Span:
+		return true
+	}
+	return false
+}
+
+// inlineChildren renders n's children as a single run of inline Markdown,
+// as used inside headings, emphasis, and links.
+func (mw *markdownWriter) inlineChildren(n *html.Node) (string, error) {
+	sub := &markdownWriter{opts: mw.opts}
+	if err := sub.children(n); err != nil {
+		return "", err
+	}
+	mw.refs = append(mw.refs, sub.refs...)
+	return sub.para.String(), nil
+}
+
+func (mw *markdownWriter) link(n *html.Node) error {
+	href := ElAttr(n, "href")
+	if mw.opts.BaseURL != "" {
+		if resolved, err := resolveURL(mw.opts.BaseURL, href); err == nil {
+			href = resolved
+		}
+	}
+
+	text, err := mw.inlineChildren(n)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+
+	if mw.opts.LinkStyle == LinkReference {
+		mw.refs = append(mw.refs, href)
+		fmt.Fprintf(&mw.para, "[%s][%d]", text, len(mw.refs))
+		return nil
+	}
+
+	fmt.Fprintf(&mw.para, "[%s](%s)", text, href)
+	return nil
+}
+
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+func (mw *markdownWriter) list(n *html.Node) (string, error) {
+	ordered := n.DataAtom == atom.Ol
+
+	var lines []string
+	idx := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		idx++
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(idx) + ". "
+		}
+
+		sub := &markdownWriter{opts: mw.opts}
+		if err := sub.children(c); err != nil {
+			return "", err
+		}
+		sub.flushParagraph()
+		mw.refs = append(mw.refs, sub.refs...)
+
+		body := indentContinuation(strings.Join(sub.blocks, "\n\n"), strings.Repeat(" ", len(marker)))
+		lines = append(lines, marker+body)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// indentContinuation indents every line of s after the first by pad,
+// so that wrapped or nested content lines up under a list marker.
+func indentContinuation(s, pad string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = pad + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (mw *markdownWriter) codeBlock(n *html.Node) (string, error) {
+	target := n
+	if c := FindEl(n, func(e *html.Node) bool { return e.DataAtom == atom.Code }); c != nil {
+		target = c
+	}
+
+	text, err := Text(target)
+	if err != nil {
+		return "", err
+	}
+	text = strings.Trim(text, "\n")
+
+	var lang string
+	if target != n {
+		for _, class := range strings.Fields(ElAttr(target, "class")) {
+			if after, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = after
+				break
+			}
+		}
+	}
+
+	fence := strings.Repeat(string(mw.opts.FenceChar), fenceLen(text, mw.opts.FenceChar))
+	return fence + lang + "\n" + text + "\n" + fence, nil
+}
+
+// fenceLen returns the length of the fence needed to wrap text
+// without being closed early by a run of fenceChar that already
+// appears in it: one longer than the longest such run, or 3,
+// whichever is greater.
+func fenceLen(text string, fenceChar byte) int {
+	const minLen = 3
+	longest, run := 0, 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == fenceChar {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest >= minLen {
+		return longest + 1
+	}
+	return minLen
+}
+
+func (mw *markdownWriter) table(n *html.Node) (string, error) {
+	trs := slices.Collect(FindAllEls(n, func(e *html.Node) bool { return e.DataAtom == atom.Tr }))
+
+	var header []string
+	var rows [][]string
+	for i, tr := range trs {
+		var cells []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || (c.DataAtom != atom.Td && c.DataAtom != atom.Th) {
+				continue
+			}
+			sub := &markdownWriter{opts: mw.opts}
+			if err := sub.children(c); err != nil {
+				return "", err
+			}
+			sub.flushParagraph()
+			mw.refs = append(mw.refs, sub.refs...)
+			cells = append(cells, escapeTableCell(strings.Join(sub.blocks, " ")))
+		}
+		if i == 0 {
+			header = cells
+		} else {
+			rows = append(rows, cells)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "| %s |\n", strings.Join(header, " | "))
+	b.WriteByte('|')
+	for range header {
+		b.WriteString(" --- |")
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&b, "\n| %s |", strings.Join(row, " | "))
+	}
+
+	return b.String(), nil
+}
+
+// escapeTableCell escapes the `|` characters in s so that cell
+// content can't be mistaken for a column separator when it's
+// joined into a table row.
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// escapeMarkdown escapes the characters that would otherwise be
+// interpreted as Markdown syntax if they appeared in plain text.
+// If atLineStart is true, s is also checked for a leading heading,
+// list, or blockquote marker, which is escaped too.
+func escapeMarkdown(s string, atLineStart bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '*', '_', '`', '[', ']':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	if atLineStart {
+		return escapeLeadingMarker(b.String())
+	}
+	return b.String()
+}
+
+// escapeLeadingMarker escapes a leading `#`, `-`, `>`, or ordered-list
+// marker (`1.`, `2)`, etc.) in s, so that plain text starting a line
+// doesn't get reinterpreted as a heading, list item, or blockquote.
+func escapeLeadingMarker(s string) string {
+	if s == "" {
+		return s
+	}
+
+	switch s[0] {
+	case '#', '-', '>':
+		return `\` + s
+	}
+
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > 0 && i < len(s) && (s[i] == '.' || s[i] == ')') {
+		return s[:i] + `\` + s[i:]
+	}
+
+	return s
+}
@@ -0,0 +1,71 @@
+package htree
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestMarkdown(t *testing.T) {
+	cases := []struct {
+		html, want string
+	}{
+		{
+			html: "<h1>Title</h1><p>Some <strong>bold</strong> and <em>italic</em> text.</p>",
+			want: "# Title\n\nSome **bold** and *italic* text.",
+		},
+		{
+			html: `<ul><li>one</li><li>two</li></ul>`,
+			want: "- one\n- two",
+		},
+		{
+			html: `<a href="https://example.com">link</a>`,
+			want: "[link](https://example.com)",
+		},
+		{
+			html: `<pre><code class="language-go">x := 1</code></pre>`,
+			want: "```go\nx := 1\n```",
+		},
+		{
+			html: `<blockquote><p>quoted</p></blockquote>`,
+			want: "> quoted",
+		},
+		{
+			html: `<table><tr><th>A</th><th>B</th></tr><tr><td>1 | 2</td><td>x</td></tr></table>`,
+			want: "| A | B |\n| --- | --- |\n| 1 \\| 2 | x |",
+		},
+		{
+			html: `<p># not a heading</p>`,
+			want: `\# not a heading`,
+		},
+		{
+			html: `<p>- not a list</p>`,
+			want: `\- not a list`,
+		},
+		{
+			html: `<p>1. not a list</p>`,
+			want: `1\. not a list`,
+		},
+		{
+			html: "<pre><code>```\nnested fence\n```</code></pre>",
+			want: "````\n```\nnested fence\n```\n````",
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(c.html, func(t *testing.T) {
+			node, err := html.Parse(strings.NewReader(c.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Markdown(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("case %d: got %q, want %q", i, got, c.want)
+			}
+		})
+	}
+}
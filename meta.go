@@ -0,0 +1,153 @@
+package htree
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// MetaInfo holds the metadata extracted from a document's <head>
+// by [ExtractMeta].
+type MetaInfo struct {
+	// Title is the content of the <title> element.
+	Title string
+
+	// CanonicalURL is the href of <link rel="canonical">.
+	CanonicalURL string
+
+	// Meta holds <meta name="..." content="..."> values
+	// that are not OpenGraph, Twitter Card, or Dublin Core metadata,
+	// plus <meta property="..." content="..."> values
+	// whose property has no "og:" prefix.
+	Meta map[string]string
+
+	// OpenGraph holds <meta property="og:..." content="..."> values,
+	// keyed without the "og:" prefix.
+	OpenGraph map[string]string
+
+	// Twitter holds <meta name="twitter:..." content="..."> values,
+	// keyed without the "twitter:" prefix.
+	Twitter map[string]string
+
+	// DublinCore holds <meta name="DC..." content="..."> values,
+	// keyed without the "DC." prefix.
+	DublinCore map[string]string
+
+	// HTTPEquiv holds <meta http-equiv="..." content="..."> values,
+	// keyed by the lowercased http-equiv name.
+	HTTPEquiv map[string]string
+
+	// JSONLDBlocks holds the raw text content of every
+	// <script type="application/ld+json"> element, in document order.
+	JSONLDBlocks []string
+}
+
+// Canonical returns m.CanonicalURL.
+func (m *MetaInfo) Canonical() string {
+	return m.CanonicalURL
+}
+
+// OG returns m's OpenGraph value for key (e.g. "image", "title"),
+// or "" if there is none.
+func (m *MetaInfo) OG(key string) string {
+	return m.OpenGraph[key]
+}
+
+// JSONLD returns the raw JSON text of every
+// <script type="application/ld+json"> block found in the document.
+func (m *MetaInfo) JSONLD() []string {
+	return m.JSONLDBlocks
+}
+
+// ExtractMeta walks root looking for <title>, <meta>, and
+// <link rel="canonical"> elements, plus any
+// <script type="application/ld+json"> blocks,
+// and returns what it finds.
+//
+// It stops as soon as it leaves <head> -
+// the underlying [FindAllEls] predicate returns true for <body>,
+// which both halts descent into the body
+// and signals ExtractMeta to stop walking any further -
+// so a huge document body is never visited.
+func ExtractMeta(root *html.Node) *MetaInfo {
+	mi := &MetaInfo{
+		Meta:       map[string]string{},
+		OpenGraph:  map[string]string{},
+		Twitter:    map[string]string{},
+		DublinCore: map[string]string{},
+		HTTPEquiv:  map[string]string{},
+	}
+
+	pred := func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Body, atom.Title, atom.Meta, atom.Link, atom.Script:
+			return true
+		}
+		return false
+	}
+
+	for n := range FindAllEls(root, pred) {
+		if n.DataAtom == atom.Body {
+			break
+		}
+		extractMetaEl(mi, n)
+	}
+
+	return mi
+}
+
+func extractMetaEl(mi *MetaInfo, n *html.Node) {
+	switch n.DataAtom {
+	case atom.Title:
+		if mi.Title == "" {
+			if s, err := Text(n); err == nil {
+				mi.Title = s
+			}
+		}
+
+	case atom.Link:
+		if strings.EqualFold(ElAttr(n, "rel"), "canonical") {
+			mi.CanonicalURL = ElAttr(n, "href")
+		}
+
+	case atom.Meta:
+		extractMetaTag(mi, n)
+
+	case atom.Script:
+		if strings.EqualFold(ElAttr(n, "type"), "application/ld+json") {
+			if c := n.FirstChild; c != nil && c.Type == html.TextNode {
+				mi.JSONLDBlocks = append(mi.JSONLDBlocks, strings.TrimSpace(c.Data))
+			}
+		}
+	}
+}
+
+func extractMetaTag(mi *MetaInfo, n *html.Node) {
+	var (
+		name      = ElAttr(n, "name")
+		property  = ElAttr(n, "property")
+		content   = ElAttr(n, "content")
+		httpEquiv = ElAttr(n, "http-equiv")
+	)
+
+	switch {
+	case httpEquiv != "":
+		mi.HTTPEquiv[strings.ToLower(httpEquiv)] = content
+
+	case strings.HasPrefix(property, "og:"):
+		mi.OpenGraph[strings.TrimPrefix(property, "og:")] = content
+
+	case strings.HasPrefix(strings.ToLower(name), "twitter:"):
+		mi.Twitter[name[len("twitter:"):]] = content
+
+	case strings.HasPrefix(strings.ToLower(name), "dc."):
+		mi.DublinCore[name[len("dc."):]] = content
+
+	case name != "":
+		mi.Meta[name] = content
+
+	case property != "":
+		mi.Meta[property] = content
+	}
+}
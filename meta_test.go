@@ -0,0 +1,61 @@
+package htree
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractMeta(t *testing.T) {
+	const doc = `<html><head>
+<title>My Page</title>
+<link rel="canonical" href="https://example.com/page">
+<meta name="description" content="a page">
+<meta property="og:title" content="OG Title">
+<meta property="og:image" content="https://example.com/img.png">
+<meta name="twitter:card" content="summary">
+<meta name="DC.creator" content="Jane Doe">
+<meta http-equiv="refresh" content="30">
+<script type="application/ld+json">{"@type":"Article"}</script>
+</head>
+<body>
+<p>This should never be visited</p>
+<meta name="description" content="body meta, must be ignored">
+</body></html>`
+
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mi := ExtractMeta(root)
+
+	if mi.Title != "My Page" {
+		t.Errorf("got title %q, want %q", mi.Title, "My Page")
+	}
+	if mi.Canonical() != "https://example.com/page" {
+		t.Errorf("got canonical %q, want %q", mi.Canonical(), "https://example.com/page")
+	}
+	if mi.Meta["description"] != "a page" {
+		t.Errorf("got description %q, want %q", mi.Meta["description"], "a page")
+	}
+	if mi.OG("title") != "OG Title" {
+		t.Errorf("got og:title %q, want %q", mi.OG("title"), "OG Title")
+	}
+	if mi.OG("image") != "https://example.com/img.png" {
+		t.Errorf("got og:image %q, want %q", mi.OG("image"), "https://example.com/img.png")
+	}
+	if mi.Twitter["card"] != "summary" {
+		t.Errorf("got twitter:card %q, want %q", mi.Twitter["card"], "summary")
+	}
+	if mi.DublinCore["creator"] != "Jane Doe" {
+		t.Errorf("got dc.creator %q, want %q", mi.DublinCore["creator"], "Jane Doe")
+	}
+	if mi.HTTPEquiv["refresh"] != "30" {
+		t.Errorf("got http-equiv refresh %q, want %q", mi.HTTPEquiv["refresh"], "30")
+	}
+	if len(mi.JSONLD()) != 1 || mi.JSONLD()[0] != `{"@type":"Article"}` {
+		t.Errorf("got JSON-LD %v", mi.JSONLD())
+	}
+}
@@ -0,0 +1,272 @@
+package htree
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// detach removes n from its parent's child list, if any,
+// fixing up the neighboring sibling pointers.
+// n's own children are left untouched.
+func detach(n *html.Node) {
+	if n.Parent != nil {
+		if n.PrevSibling != nil {
+			n.PrevSibling.NextSibling = n.NextSibling
+		} else {
+			n.Parent.FirstChild = n.NextSibling
+		}
+		if n.NextSibling != nil {
+			n.NextSibling.PrevSibling = n.PrevSibling
+		} else {
+			n.Parent.LastChild = n.PrevSibling
+		}
+	}
+	n.Parent = nil
+	n.PrevSibling = nil
+	n.NextSibling = nil
+}
+
+// AppendChild detaches child from wherever it currently is
+// and adds it as the last child of parent.
+func AppendChild(parent, child *html.Node) {
+	detach(child)
+	child.Parent = parent
+	if parent.LastChild != nil {
+		parent.LastChild.NextSibling = child
+		child.PrevSibling = parent.LastChild
+	} else {
+		parent.FirstChild = child
+	}
+	parent.LastChild = child
+}
+
+// PrependChild detaches child from wherever it currently is
+// and adds it as the first child of parent.
+func PrependChild(parent, child *html.Node) {
+	detach(child)
+	child.Parent = parent
+	if parent.FirstChild != nil {
+		parent.FirstChild.PrevSibling = child
+		child.NextSibling = parent.FirstChild
+	} else {
+		parent.LastChild = child
+	}
+	parent.FirstChild = child
+}
+
+// InsertBefore detaches new from wherever it currently is
+// and inserts it immediately before ref, as a sibling of ref.
+func InsertBefore(ref, new *html.Node) {
+	detach(new)
+
+	parent := ref.Parent
+	prev := ref.PrevSibling
+
+	new.Parent = parent
+	new.PrevSibling = prev
+	new.NextSibling = ref
+	if prev != nil {
+		prev.NextSibling = new
+	} else if parent != nil {
+		parent.FirstChild = new
+	}
+	ref.PrevSibling = new
+}
+
+// InsertAfter detaches new from wherever it currently is
+// and inserts it immediately after ref, as a sibling of ref.
+func InsertAfter(ref, new *html.Node) {
+	detach(new)
+
+	parent := ref.Parent
+	next := ref.NextSibling
+
+	new.Parent = parent
+	new.PrevSibling = ref
+	new.NextSibling = next
+	if next != nil {
+		next.PrevSibling = new
+	} else if parent != nil {
+		parent.LastChild = new
+	}
+	ref.NextSibling = new
+}
+
+// Replace detaches old from its parent (if any) and puts new in its place.
+// new is detached from wherever it currently is first.
+// old is left fully detached, with its own children untouched.
+func Replace(old, new *html.Node) {
+	detach(new)
+
+	parent := old.Parent
+	prev := old.PrevSibling
+	next := old.NextSibling
+
+	detach(old)
+
+	new.Parent = parent
+	new.PrevSibling = prev
+	new.NextSibling = next
+	if prev != nil {
+		prev.NextSibling = new
+	} else if parent != nil {
+		parent.FirstChild = new
+	}
+	if next != nil {
+		next.PrevSibling = new
+	} else if parent != nil {
+		parent.LastChild = new
+	}
+}
+
+// Wrap puts wrapper in n's place in the tree,
+// then makes n the last child of wrapper.
+// wrapper's own existing children, if any, come before n.
+func Wrap(n, wrapper *html.Node) {
+	Replace(n, wrapper)
+	AppendChild(wrapper, n)
+}
+
+// Unwrap removes n from the tree,
+// promoting n's children to take its place among n's former siblings.
+// n is left fully detached, with no parent or children.
+// Unwrap does nothing if n has no parent.
+func Unwrap(n *html.Node) {
+	parent := n.Parent
+	if parent == nil {
+		return
+	}
+
+	first, last := n.FirstChild, n.LastChild
+	prev, next := n.PrevSibling, n.NextSibling
+
+	if first == nil {
+		detach(n)
+		return
+	}
+
+	for c := first; c != nil; c = c.NextSibling {
+		c.Parent = parent
+	}
+
+	first.PrevSibling = prev
+	if prev != nil {
+		prev.NextSibling = first
+	} else {
+		parent.FirstChild = first
+	}
+
+	last.NextSibling = next
+	if next != nil {
+		next.PrevSibling = last
+	} else {
+		parent.LastChild = last
+	}
+
+	n.Parent, n.PrevSibling, n.NextSibling = nil, nil, nil
+	n.FirstChild, n.LastChild = nil, nil
+}
+
+// SetAttr sets n's attribute key to val,
+// adding the attribute if n does not already have one by that name.
+func SetAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// RemoveAttr removes n's attribute key, if it has one.
+func RemoveAttr(n *html.Node, key string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr = slices.Delete(n.Attr, i, i+1)
+			return
+		}
+	}
+}
+
+// AddClass adds class to n's class attribute,
+// doing nothing if n already has it.
+func AddClass(n *html.Node, class string) {
+	classes := strings.Fields(ElAttr(n, "class"))
+	if slices.Contains(classes, class) {
+		return
+	}
+	SetAttr(n, "class", strings.Join(append(classes, class), " "))
+}
+
+// RemoveClass removes class from n's class attribute, if present.
+func RemoveClass(n *html.Node, class string) {
+	classes := strings.Fields(ElAttr(n, "class"))
+	classes = slices.DeleteFunc(classes, func(c string) bool { return c == class })
+	if len(classes) == 0 {
+		RemoveAttr(n, "class")
+		return
+	}
+	SetAttr(n, "class", strings.Join(classes, " "))
+}
+
+// ToggleClass adds class to n's class attribute if it is not already present,
+// or removes it if it is.
+func ToggleClass(n *html.Node, class string) {
+	if ElClassContains(n, class) {
+		RemoveClass(n, class)
+	} else {
+		AddClass(n, class)
+	}
+}
+
+// Map returns a copy of the tree rooted at node,
+// with every node passed through fn.
+// If fn(n) returns nil, n and its subtree are omitted from the result.
+// If node itself maps to nil, Map returns nil.
+//
+// fn receives each node with its original children still attached;
+// Map is responsible for attaching the (already-mapped) children
+// to a copy of whatever node fn returns,
+// so Map itself modifies neither the input tree nor the nodes fn returns.
+func Map(node *html.Node, fn func(*html.Node) *html.Node) *html.Node {
+	mapped := fn(node)
+	if mapped == nil {
+		return nil
+	}
+	cp := *mapped
+	mapped = &cp
+
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if m := Map(child, fn); m != nil {
+			children = append(children, m)
+		}
+	}
+
+	for i, child := range children {
+		if i == 0 {
+			child.PrevSibling = nil
+		} else {
+			child.PrevSibling = children[i-1]
+		}
+		if i == len(children)-1 {
+			child.NextSibling = nil
+		} else {
+			child.NextSibling = children[i+1]
+		}
+		child.Parent = mapped
+	}
+
+	if len(children) > 0 {
+		mapped.FirstChild = children[0]
+		mapped.LastChild = children[len(children)-1]
+	} else {
+		mapped.FirstChild = nil
+		mapped.LastChild = nil
+	}
+
+	return mapped
+}
@@ -0,0 +1,285 @@
+package htree
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func parseFragment(t *testing.T, s string) *html.Node {
+	t.Helper()
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestReplace(t *testing.T) {
+	root := parseFragment(t, `<div><p>old</p></div>`)
+	old := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.P })
+	if old == nil {
+		t.Fatal("no p")
+	}
+
+	new := &html.Node{Type: html.ElementNode, Data: "span", DataAtom: atom.Span}
+	Replace(old, new)
+
+	got, err := Text(FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Div }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+	if FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Span }) == nil {
+		t.Error("span not found after Replace")
+	}
+	if old.Parent != nil {
+		t.Error("old still has a parent after Replace")
+	}
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	root := parseFragment(t, `<div><p>hi</p></div>`)
+	p := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.P })
+
+	wrapper := &html.Node{Type: html.ElementNode, Data: "section", DataAtom: atom.Section}
+	Wrap(p, wrapper)
+
+	if p.Parent != wrapper {
+		t.Fatal("p's parent is not wrapper after Wrap")
+	}
+	section := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Section })
+	if section == nil {
+		t.Fatal("section not found after Wrap")
+	}
+
+	Unwrap(section)
+	if FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Section }) != nil {
+		t.Error("section still present after Unwrap")
+	}
+	if p.Parent == nil || p.Parent.DataAtom != atom.Div {
+		t.Error("p was not reattached to div after Unwrap")
+	}
+}
+
+func TestInsertAfter(t *testing.T) {
+	root := parseFragment(t, `<ul><li>a</li><li>c</li></ul>`)
+	ul := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Ul })
+
+	var items []*html.Node
+	for c := ul.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			items = append(items, c)
+		}
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d li, want 2", len(items))
+	}
+
+	b := &html.Node{Type: html.ElementNode, Data: "li", DataAtom: atom.Li, FirstChild: &html.Node{Type: html.TextNode, Data: "b"}}
+	InsertAfter(items[0], b)
+
+	var got []string
+	for c := ul.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		s, err := Text(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestReplaceAdjacentSibling(t *testing.T) {
+	root := parseFragment(t, `<ul><li>a</li><li>b</li><li>c</li></ul>`)
+	ul := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Ul })
+
+	var items []*html.Node
+	for c := ul.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			items = append(items, c)
+		}
+	}
+	a, b := items[0], items[1]
+
+	Replace(a, b)
+
+	if b.NextSibling == b {
+		t.Fatal("b.NextSibling points to b itself after Replace(a, b)")
+	}
+
+	var got []string
+	for n := ul.FirstChild; n != nil && len(got) <= len(items); n = n.NextSibling {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		s, err := Text(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	want := []string{"b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestReplaceWithPrevSibling(t *testing.T) {
+	root := parseFragment(t, `<ul><li>a</li><li>b</li><li>c</li></ul>`)
+	ul := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Ul })
+
+	var items []*html.Node
+	for c := ul.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			items = append(items, c)
+		}
+	}
+	a, b := items[0], items[1]
+
+	Replace(b, a)
+
+	var got []string
+	for n := ul.FirstChild; n != nil && len(got) <= len(items); n = n.NextSibling {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		s, err := Text(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	want := []string{"a", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestInsertAdjacentNoOp(t *testing.T) {
+	root := parseFragment(t, `<ul><li>a</li><li>b</li></ul>`)
+	ul := FindEl(root, func(n *html.Node) bool { return n.DataAtom == atom.Ul })
+
+	var items []*html.Node
+	for c := ul.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			items = append(items, c)
+		}
+	}
+	a, b := items[0], items[1]
+
+	InsertAfter(a, b)  // b is already a's next sibling
+	InsertBefore(b, a) // a is already b's previous sibling
+
+	var got []string
+	for n := ul.FirstChild; n != nil && len(got) <= len(items); n = n.NextSibling {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		s, err := Text(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	want := []string{"a", "b"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAttrAndClassHelpers(t *testing.T) {
+	n := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+
+	SetAttr(n, "id", "x")
+	if ElAttr(n, "id") != "x" {
+		t.Fatalf("got %q, want %q", ElAttr(n, "id"), "x")
+	}
+	SetAttr(n, "id", "y")
+	if ElAttr(n, "id") != "y" {
+		t.Fatalf("got %q, want %q", ElAttr(n, "id"), "y")
+	}
+	RemoveAttr(n, "id")
+	if ElAttr(n, "id") != "" {
+		t.Errorf("id attribute still present after RemoveAttr")
+	}
+
+	AddClass(n, "a")
+	AddClass(n, "b")
+	AddClass(n, "a")
+	if ElAttr(n, "class") != "a b" {
+		t.Fatalf("got %q, want %q", ElAttr(n, "class"), "a b")
+	}
+
+	RemoveClass(n, "a")
+	if ElAttr(n, "class") != "b" {
+		t.Fatalf("got %q, want %q", ElAttr(n, "class"), "b")
+	}
+
+	ToggleClass(n, "b")
+	if ElClassContains(n, "b") {
+		t.Error("b still present after ToggleClass")
+	}
+	ToggleClass(n, "b")
+	if !ElClassContains(n, "b") {
+		t.Error("b not present after second ToggleClass")
+	}
+}
+
+func TestMap(t *testing.T) {
+	root := parseFragment(t, `<div><p>x</p><p>y</p></div>`)
+
+	mapped := Map(root, func(n *html.Node) *html.Node {
+		if n.Type == html.ElementNode && n.DataAtom == atom.P {
+			cp := *n
+			cp.Attr = append([]html.Attribute{}, n.Attr...)
+			SetAttr(&cp, "class", "mapped")
+			return &cp
+		}
+		return n
+	})
+
+	var classes []string
+	for n := range FindAllEls(mapped, func(n *html.Node) bool { return n.DataAtom == atom.P }) {
+		classes = append(classes, ElAttr(n, "class"))
+	}
+	want := []string{"mapped", "mapped"}
+	if len(classes) != len(want) {
+		t.Fatalf("got %v, want %v", classes, want)
+	}
+	for i := range want {
+		if classes[i] != want[i] {
+			t.Errorf("got %v, want %v", classes, want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package htree
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Policy describes what [Sanitize] allows through.
+// The zero value allows nothing:
+// every element is dropped (or unwrapped, if UnwrapDisallowed is set)
+// and every attribute is stripped.
+type Policy struct {
+	// AllowedElements is the set of element tag names
+	// (lowercase, e.g. "p", "a", "img") that Sanitize keeps.
+	AllowedElements map[string]bool
+
+	// AllowedAttributes maps an element tag name to the attribute
+	// names allowed on it. The special key "*" applies to every
+	// element, in addition to any tag-specific entry.
+	AllowedAttributes map[string][]string
+
+	// AllowedSchemes is the set of URL schemes (lowercase, without
+	// the trailing colon, e.g. "http", "https", "mailto") allowed
+	// in URL-valued attributes such as href and src.
+	// A relative URL (no scheme) is always allowed.
+	AllowedSchemes map[string]bool
+
+	// AllowedStyleProperties is the set of CSS property names
+	// (lowercase, e.g. "color") allowed in a style attribute.
+	// If empty, style attributes are stripped entirely.
+	AllowedStyleProperties map[string]bool
+
+	// UnwrapDisallowed, if true, replaces a disallowed element with
+	// its own (sanitized) children instead of dropping it outright.
+	UnwrapDisallowed bool
+}
+
+// urlAttrs is the set of attributes whose value is a URL
+// and therefore subject to AllowedSchemes.
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "action": true,
+	"formaction": true, "poster": true, "cite": true, "background": true,
+}
+
+// Sanitize returns a copy of node and its children,
+// filtered through policy: disallowed elements are dropped (or, if
+// policy.UnwrapDisallowed is set, replaced by their own children),
+// disallowed attributes are stripped, URL-valued attributes with a
+// disallowed scheme are stripped, and inline style properties not in
+// policy.AllowedStyleProperties are removed.
+//
+// Comments, doctypes, and event-handler (`on...`) or `srcdoc`
+// attributes are always stripped, regardless of policy.
+//
+// If node itself is dropped and nothing takes its place,
+// Sanitize returns nil.
+// If UnwrapDisallowed promotes node's children to take its place,
+// a single promoted node is returned as is,
+// and more than one are returned as the children of a new DocumentNode.
+func Sanitize(node *html.Node, policy *Policy) *html.Node {
+	results := sanitizeNode(node, policy)
+	switch len(results) {
+	case 0:
+		return nil
+	case 1:
+		return results[0]
+	default:
+		doc := &html.Node{Type: html.DocumentNode}
+		for _, r := range results {
+			AppendChild(doc, r)
+		}
+		return doc
+	}
+}
+
+func sanitizeNode(n *html.Node, policy *Policy) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+
+	case html.DocumentNode:
+		doc := &html.Node{Type: html.DocumentNode}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			for _, k := range sanitizeNode(c, policy) {
+				AppendChild(doc, k)
+			}
+		}
+		return []*html.Node{doc}
+
+	case html.ElementNode:
+		return sanitizeElement(n, policy)
+
+	default: // CommentNode, DoctypeNode, RawNode, ErrorNode
+		return nil
+	}
+}
+
+// neverUnwrap holds elements whose content must be dropped along with
+// the element itself: unwrapping would leak raw script/style source as
+// if it were prose.
+var neverUnwrap = map[string]bool{"script": true, "style": true}
+
+func sanitizeElement(n *html.Node, policy *Policy) []*html.Node {
+	if !policy.AllowedElements[n.Data] {
+		if !policy.UnwrapDisallowed || neverUnwrap[n.Data] {
+			return nil
+		}
+		var kids []*html.Node
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			kids = append(kids, sanitizeNode(c, policy)...)
+		}
+		return kids
+	}
+
+	cp := &html.Node{Type: html.ElementNode, Data: n.Data, DataAtom: n.DataAtom, Namespace: n.Namespace}
+	for _, a := range n.Attr {
+		if val, ok := sanitizeAttr(n.Data, a, policy); ok {
+			cp.Attr = append(cp.Attr, html.Attribute{Namespace: a.Namespace, Key: a.Key, Val: val})
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		for _, k := range sanitizeNode(c, policy) {
+			AppendChild(cp, k)
+		}
+	}
+
+	return []*html.Node{cp}
+}
+
+// sanitizeAttr reports whether a is allowed on an element named tag,
+// and the (possibly rewritten, e.g. for style) value to keep.
+func sanitizeAttr(tag string, a html.Attribute, policy *Policy) (string, bool) {
+	key := strings.ToLower(a.Key)
+
+	if key == "srcdoc" || strings.HasPrefix(key, "on") {
+		return "", false
+	}
+	if !attrNameAllowed(policy, tag, key) {
+		return "", false
+	}
+	if urlAttrs[key] {
+		if scheme := urlScheme(a.Val); scheme != "" && !policy.AllowedSchemes[scheme] {
+			return "", false
+		}
+	}
+	if key == "style" {
+		val := filterStyle(a.Val, policy.AllowedStyleProperties)
+		return val, val != ""
+	}
+
+	return a.Val, true
+}
+
+func attrNameAllowed(policy *Policy, tag, key string) bool {
+	for _, k := range policy.AllowedAttributes[tag] {
+		if k == key {
+			return true
+		}
+	}
+	for _, k := range policy.AllowedAttributes["*"] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// urlScheme returns the lowercase scheme of a URL-like string,
+// or "" if it has none (e.g. a relative URL).
+//
+// Browsers strip ASCII tab and newline characters from a URL,
+// and leading spaces and control characters,
+// before looking for its scheme, so values such as
+// "jav\tascript:alert(1)" and " javascript:alert(1)"
+// are still treated as javascript: URLs; urlScheme does the same,
+// so that stripping it doesn't let a disallowed scheme slip past as
+// if it were a harmless relative reference.
+func urlScheme(v string) string {
+	v = stripURLWhitespace(v)
+	v = strings.TrimLeftFunc(v, func(r rune) bool { return r <= ' ' })
+
+	i := strings.IndexByte(v, ':')
+	if i < 0 {
+		return ""
+	}
+	scheme := v[:i]
+	for _, c := range scheme {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '+', c == '-', c == '.':
+		default:
+			return ""
+		}
+	}
+	return strings.ToLower(scheme)
+}
+
+// stripURLWhitespace removes the ASCII tab, CR, and LF characters
+// that browsers strip from a URL before parsing it.
+func stripURLWhitespace(v string) string {
+	if strings.IndexAny(v, "\t\r\n") < 0 {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c != '\t' && c != '\r' && c != '\n' {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// filterStyle keeps only the declarations of style
+// whose property is in allowed.
+func filterStyle(style string, allowed map[string]bool) string {
+	if len(allowed) == 0 {
+		return ""
+	}
+	var kept []string
+	for _, decl := range strings.Split(style, ";") {
+		prop, val, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		if allowed[prop] {
+			kept = append(kept, prop+": "+strings.TrimSpace(val))
+		}
+	}
+	return strings.Join(kept, "; ")
+}
+
+func tagSet(tags ...string) map[string]bool {
+	m := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		m[t] = true
+	}
+	return m
+}
+
+// StrictText returns a policy allowing only minimal inline text
+// formatting and nothing else: no links, no images, no attributes.
+func StrictText() *Policy {
+	return &Policy{
+		AllowedElements:  tagSet("p", "br", "b", "strong", "i", "em", "ul", "ol", "li", "span"),
+		UnwrapDisallowed: true,
+	}
+}
+
+// UserContent returns a policy suitable for user-submitted
+// comment or article bodies: common text formatting, links, and
+// images, with http/https/mailto URLs only.
+func UserContent() *Policy {
+	return &Policy{
+		AllowedElements: tagSet(
+			"p", "br", "b", "strong", "i", "em", "u", "ul", "ol", "li",
+			"a", "blockquote", "code", "pre",
+			"h1", "h2", "h3", "h4", "h5", "h6",
+			"img", "span", "div",
+		),
+		AllowedAttributes: map[string][]string{
+			"a":   {"href", "title"},
+			"img": {"src", "alt", "title", "width", "height"},
+		},
+		AllowedSchemes:   tagSet("http", "https", "mailto"),
+		UnwrapDisallowed: true,
+	}
+}
+
+// EmailSafe returns a policy suitable for HTML email bodies:
+// basic formatting, tables, images, and a small allow-list of
+// inline style properties, since many mail clients ignore
+// `<style>` blocks and external stylesheets entirely.
+func EmailSafe() *Policy {
+	return &Policy{
+		AllowedElements: tagSet(
+			"p", "br", "b", "strong", "i", "em", "u", "ul", "ol", "li",
+			"a", "table", "thead", "tbody", "tr", "td", "th",
+			"span", "div", "img",
+			"h1", "h2", "h3", "h4", "h5", "h6",
+		),
+		AllowedAttributes: map[string][]string{
+			"a":   {"href"},
+			"img": {"src", "alt", "width", "height"},
+			"td":  {"colspan", "rowspan"},
+			"th":  {"colspan", "rowspan"},
+			"*":   {"style"},
+		},
+		AllowedSchemes:         tagSet("http", "https", "mailto"),
+		AllowedStyleProperties: tagSet("color", "background-color", "font-weight", "text-align", "padding", "margin"),
+		UnwrapDisallowed:       true,
+	}
+}
@@ -0,0 +1,107 @@
+package htree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSanitize(t *testing.T) {
+	const doc = `<div>
+<p>hello <b>world</b></p>
+<script>alert(1)</script>
+<img src="javascript:alert(1)" onerror="alert(1)">
+<a href="https://example.com" onclick="steal()">link</a>
+</div>`
+
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clean := Sanitize(root, UserContent())
+	if clean == nil {
+		t.Fatal("Sanitize returned nil")
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, clean); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if strings.Contains(out, "script") {
+		t.Errorf("output contains script: %s", out)
+	}
+	if strings.Contains(out, "onerror") || strings.Contains(out, "onclick") {
+		t.Errorf("output contains an event handler: %s", out)
+	}
+	if strings.Contains(out, "javascript:") {
+		t.Errorf("output contains a javascript: URL: %s", out)
+	}
+	if !strings.Contains(out, `href="https://example.com"`) {
+		t.Errorf("output is missing the safe link: %s", out)
+	}
+	if !strings.Contains(out, "<b>world</b>") {
+		t.Errorf("output is missing allowed formatting: %s", out)
+	}
+}
+
+func TestSanitizeUnwrap(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<div><p>a <span class="x">b</span> c</p></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &Policy{
+		AllowedElements:  tagSet("p"),
+		UnwrapDisallowed: true,
+	}
+	clean := Sanitize(root, policy)
+
+	text, err := Text(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "a b c" {
+		t.Errorf("got %q, want %q", text, "a b c")
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, clean); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "span") {
+		t.Errorf("span should have been unwrapped: %s", buf.String())
+	}
+}
+
+func TestURLSchemeObfuscation(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<a href="jav` + "\t" + `ascript:alert(1)">click</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clean := Sanitize(root, UserContent())
+	if clean == nil {
+		t.Fatal("Sanitize returned nil")
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, clean); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "href") {
+		t.Errorf("tab-obfuscated javascript: URL was not stripped: %s", buf.String())
+	}
+}
+
+func TestFilterStyle(t *testing.T) {
+	got := filterStyle("color: red; position: absolute; background-color:blue", tagSet("color", "background-color"))
+	want := "color: red; background-color: blue"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+package htree
+
+import (
+	"io"
+	"iter"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PathElement describes one ancestor of a token event,
+// as seen by [WalkTokens] and friends.
+// Ancestors are recorded without materializing a full *html.Node,
+// so that predicates can match on ancestor tag/class/id
+// while still streaming through huge documents.
+type PathElement struct {
+	Atom atom.Atom
+	Attr []html.Attribute
+}
+
+// PathAttr returns pe's value for the attribute key,
+// or "" if pe has no such attribute.
+func PathAttr(pe PathElement, key string) string {
+	for _, a := range pe.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// PathClassContains tells whether pe has a class attribute
+// containing the class name probe.
+func PathClassContains(pe PathElement, probe string) bool {
+	for _, c := range strings.Fields(PathAttr(pe, "class")) {
+		if c == probe {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenEvent is one event produced by [WalkTokens]:
+// a single token from the underlying [html.Tokenizer],
+// together with the stack of its open ancestors.
+type TokenEvent struct {
+	Type     html.TokenType
+	DataAtom atom.Atom
+	Data     string
+	Attr     []html.Attribute
+
+	// Path is the sequence of open ancestors of this event,
+	// outermost first, not including the event's own tag.
+	Path []PathElement
+
+	// Depth is len(Path).
+	Depth int
+}
+
+// WalkTokens tokenizes r with [html.Tokenizer]
+// and produces an iterator over the resulting token stream,
+// without ever materializing a full *html.Node tree.
+// It is meant for documents too large to parse in one pass
+// (sitemaps, scraped archives, WARC extracts).
+//
+// Iteration stops after the first error, which is always the final
+// value produced; a clean end of input is not reported as an error.
+func WalkTokens(r io.Reader) iter.Seq2[TokenEvent, error] {
+	return func(yield func(TokenEvent, error) bool) {
+		tk := html.NewTokenizer(r)
+		var stack []PathElement
+
+		for {
+			tt := tk.Next()
+			if tt == html.ErrorToken {
+				if err := tk.Err(); err != io.EOF {
+					yield(TokenEvent{}, err)
+				}
+				return
+			}
+
+			tok := tk.Token()
+
+			if tt == html.EndTagToken && len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+			ev := TokenEvent{
+				Type:     tt,
+				DataAtom: tok.DataAtom,
+				Data:     tok.Data,
+				Attr:     tok.Attr,
+				Path:     pathSnapshot(stack),
+				Depth:    len(stack),
+			}
+
+			if !yield(ev, nil) {
+				return
+			}
+
+			if tt == html.StartTagToken && !voidElements.Has(tok.DataAtom) {
+				stack = append(stack, PathElement{Atom: tok.DataAtom, Attr: tok.Attr})
+			}
+		}
+	}
+}
+
+func pathSnapshot(stack []PathElement) []PathElement {
+	out := make([]PathElement, len(stack))
+	copy(out, stack)
+	return out
+}
+
+// FindAllTokens is like [FindAll] but operates on the token stream
+// produced by [WalkTokens],
+// yielding only the events that satisfy pred.
+func FindAllTokens(r io.Reader, pred func(TokenEvent) bool) iter.Seq2[TokenEvent, error] {
+	return func(yield func(TokenEvent, error) bool) {
+		for ev, err := range WalkTokens(r) {
+			if err != nil {
+				yield(TokenEvent{}, err)
+				return
+			}
+			if pred(ev) && !yield(ev, nil) {
+				return
+			}
+		}
+	}
+}
+
+// TextTokens is like [Text] but operates on the token stream
+// produced by [WalkTokens],
+// yielding the decoded contents of each text token
+// except those inside <script> or <style> elements.
+func TextTokens(r io.Reader) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for ev, err := range WalkTokens(r) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if ev.Type != html.TextToken {
+				continue
+			}
+			if len(ev.Path) > 0 {
+				if a := ev.Path[len(ev.Path)-1].Atom; a == atom.Script || a == atom.Style {
+					continue
+				}
+			}
+			if !yield(html.UnescapeString(ev.Data), nil) {
+				return
+			}
+		}
+	}
+}
+
+// MaterializeMatched walks the token stream produced by tokenizing r,
+// and whenever a start tag satisfies pred,
+// it builds a *html.Node subtree for that element
+// (reusing none of the surrounding document)
+// and calls fn with it.
+// fn's subtree can then be passed to ordinary node-based helpers
+// such as [Prune] or [Text].
+//
+// Matched subtrees are not descended into further;
+// MaterializeMatched resumes token-at-a-time walking
+// after the matched element's closing tag.
+func MaterializeMatched(r io.Reader, pred func(TokenEvent) bool, fn func(*html.Node) error) error {
+	tk := html.NewTokenizer(r)
+	var stack []PathElement
+
+	for {
+		tt := tk.Next()
+		if tt == html.ErrorToken {
+			if err := tk.Err(); err != io.EOF {
+				return err
+			}
+			return nil
+		}
+
+		tok := tk.Token()
+
+		if tt == html.EndTagToken {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		ev := TokenEvent{
+			Type:     tt,
+			DataAtom: tok.DataAtom,
+			Data:     tok.Data,
+			Attr:     tok.Attr,
+			Path:     pathSnapshot(stack),
+			Depth:    len(stack),
+		}
+
+		if pred(ev) {
+			root, err := materializeSubtree(tk, tok, tt)
+			if err != nil {
+				return err
+			}
+			if err := fn(root); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tt == html.StartTagToken && !voidElements.Has(tok.DataAtom) {
+			stack = append(stack, PathElement{Atom: tok.DataAtom, Attr: tok.Attr})
+		}
+	}
+}
+
+// materializeSubtree builds a *html.Node for the element described by
+// tok, consuming tokens from tk up to and including its matching end tag.
+func materializeSubtree(tk *html.Tokenizer, tok html.Token, tt html.TokenType) (*html.Node, error) {
+	root := &html.Node{Type: html.ElementNode, Data: tok.Data, DataAtom: tok.DataAtom, Attr: tok.Attr}
+	if tt == html.SelfClosingTagToken || voidElements.Has(tok.DataAtom) {
+		return root, nil
+	}
+
+	cur := root
+	for depth := 1; depth > 0; {
+		tt2 := tk.Next()
+		if tt2 == html.ErrorToken {
+			if err := tk.Err(); err != io.EOF {
+				return nil, err
+			}
+			return root, nil // unterminated input: return what we have
+		}
+
+		tok2 := tk.Token()
+		switch tt2 {
+		case html.StartTagToken:
+			child := &html.Node{Type: html.ElementNode, Data: tok2.Data, DataAtom: tok2.DataAtom, Attr: tok2.Attr}
+			appendChild(cur, child)
+			if !voidElements.Has(tok2.DataAtom) {
+				cur = child
+				depth++
+			}
+
+		case html.SelfClosingTagToken:
+			appendChild(cur, &html.Node{Type: html.ElementNode, Data: tok2.Data, DataAtom: tok2.DataAtom, Attr: tok2.Attr})
+
+		case html.EndTagToken:
+			depth--
+			if depth > 0 {
+				cur = cur.Parent
+			}
+
+		case html.TextToken:
+			appendChild(cur, &html.Node{Type: html.TextNode, Data: tok2.Data})
+
+		case html.CommentToken:
+			appendChild(cur, &html.Node{Type: html.CommentNode, Data: tok2.Data})
+		}
+	}
+
+	return root, nil
+}
+
+func appendChild(parent, child *html.Node) {
+	child.Parent = parent
+	if parent.LastChild != nil {
+		parent.LastChild.NextSibling = child
+		child.PrevSibling = parent.LastChild
+	} else {
+		parent.FirstChild = child
+	}
+	parent.LastChild = child
+}
@@ -0,0 +1,107 @@
+package htree
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestWalkTokens(t *testing.T) {
+	const doc = `<div id="a"><p class="x">one</p><p>two<script>bad()</script></p></div>`
+
+	var texts []string
+	for ev, err := range WalkTokens(strings.NewReader(doc)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type == html.TextToken {
+			texts = append(texts, ev.Data)
+		}
+	}
+
+	want := []string{"one", "two", "bad()"}
+	if len(texts) != len(want) {
+		t.Fatalf("got %v, want %v", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("got %v, want %v", texts, want)
+		}
+	}
+}
+
+func TestTextTokens(t *testing.T) {
+	const doc = `<div>x <style>y</style> z</div>`
+
+	var got []string
+	for s, err := range TextTokens(strings.NewReader(doc)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+
+	want := []string{"x ", " z"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFindAllTokens(t *testing.T) {
+	const doc = `<div id="a"><p class="x">one</p><p class="y">two</p></div>`
+
+	var classes []string
+	for ev, err := range FindAllTokens(strings.NewReader(doc), func(ev TokenEvent) bool {
+		return ev.Type == html.StartTagToken && ev.DataAtom == atom.P
+	}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range ev.Attr {
+			if a.Key == "class" {
+				classes = append(classes, a.Val)
+			}
+		}
+	}
+
+	want := []string{"x", "y"}
+	if len(classes) != len(want) {
+		t.Fatalf("got %v, want %v", classes, want)
+	}
+	for i := range want {
+		if classes[i] != want[i] {
+			t.Errorf("got %v, want %v", classes, want)
+		}
+	}
+}
+
+func TestMaterializeMatched(t *testing.T) {
+	const doc = `<div><p class="x">hello <b>world</b></p></div>`
+
+	var got string
+	err := MaterializeMatched(strings.NewReader(doc), func(ev TokenEvent) bool {
+		return ev.Type == html.StartTagToken && ev.DataAtom == atom.P
+	}, func(n *html.Node) error {
+		s, err := Text(n)
+		if err != nil {
+			return err
+		}
+		got = s
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "hello world"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,692 @@
+package xpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bobg/htree"
+	"golang.org/x/net/html"
+)
+
+// compile parses expr as a path expression:
+// a sequence of `/`- or `//`-separated steps,
+// each optionally bracketed with one or more predicates.
+func compile(expr string) (*path, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &tokenParser{toks: toks}
+	steps, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected token %q", p.peek().s)
+	}
+	return &path{steps: steps}, nil
+}
+
+// --- lexer ---
+
+type tokKind int
+
+const (
+	tokName tokKind = iota
+	tokString
+	tokNumber
+	tokSlash      // /
+	tokSlashSlash // //
+	tokAt         // @
+	tokStar       // *
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+	tokComma
+	tokAnd
+	tokOr
+	tokEq // =
+	tokNe // !=
+	tokLt // <
+	tokLe // <=
+	tokGt // >
+	tokGe // >=
+	tokEOF
+)
+
+type token struct {
+	kind tokKind
+	s    string
+	n    float64
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '/':
+			if i+1 < len(s) && s[i+1] == '/' {
+				toks = append(toks, token{kind: tokSlashSlash})
+				i += 2
+			} else {
+				toks = append(toks, token{kind: tokSlash})
+				i++
+			}
+
+		case c == '@':
+			toks = append(toks, token{kind: tokAt})
+			i++
+
+		case c == '*':
+			toks = append(toks, token{kind: tokStar})
+			i++
+
+		case c == '[':
+			toks = append(toks, token{kind: tokLBracket})
+			i++
+
+		case c == ']':
+			toks = append(toks, token{kind: tokRBracket})
+			i++
+
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen})
+			i++
+
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen})
+			i++
+
+		case c == ',':
+			toks = append(toks, token{kind: tokComma})
+			i++
+
+		case c == '=':
+			toks = append(toks, token{kind: tokEq})
+			i++
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '=':
+			toks = append(toks, token{kind: tokNe})
+			i += 2
+
+		case c == '<':
+			if i+1 < len(s) && s[i+1] == '=' {
+				toks = append(toks, token{kind: tokLe})
+				i += 2
+			} else {
+				toks = append(toks, token{kind: tokLt})
+				i++
+			}
+
+		case c == '>':
+			if i+1 < len(s) && s[i+1] == '=' {
+				toks = append(toks, token{kind: tokGe})
+				i += 2
+			} else {
+				toks = append(toks, token{kind: tokGt})
+				i++
+			}
+
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			toks = append(toks, token{kind: tokString, s: s[i+1 : j]})
+			i = j + 1
+
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", s[i:j])
+			}
+			toks = append(toks, token{kind: tokNumber, n: n})
+			i = j
+
+		case isNameStart(c):
+			j := i
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			name := s[i:j]
+			switch strings.ToLower(name) {
+			case "and":
+				toks = append(toks, token{kind: tokAnd})
+			case "or":
+				toks = append(toks, token{kind: tokOr})
+			default:
+				toks = append(toks, token{kind: tokName, s: name})
+			}
+			i = j
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return toks, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c == '-' || (c >= '0' && c <= '9')
+}
+
+// --- parser ---
+
+type tokenParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *tokenParser) atEnd() bool { return p.pos >= len(p.toks) }
+
+func (p *tokenParser) peek() token {
+	if p.atEnd() {
+		return token{kind: tokEOF}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *tokenParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *tokenParser) expect(k tokKind) (token, error) {
+	t := p.peek()
+	if t.kind != k {
+		return token{}, fmt.Errorf("unexpected token (wanted kind %d, got %d)", k, t.kind)
+	}
+	return p.next(), nil
+}
+
+// parsePath parses a (possibly relative) path expression
+// into its component steps.
+func (p *tokenParser) parsePath() ([]step, error) {
+	var steps []step
+
+	// A leading '/' or '//' just establishes the axis of the first step.
+	ax := axisChild
+	switch p.peek().kind {
+	case tokSlashSlash:
+		p.next()
+		ax = axisDescendantOrSelf
+	case tokSlash:
+		p.next()
+	}
+
+	for {
+		if !stepStarts(p.peek().kind) {
+			break
+		}
+		st, err := p.parseStep(ax)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+
+		switch p.peek().kind {
+		case tokSlashSlash:
+			p.next()
+			// The next step carries an explicit descendant-or-self axis.
+			if !stepStarts(p.peek().kind) {
+				return nil, fmt.Errorf("expected a node test")
+			}
+			ax = axisDescendantOrSelf
+		case tokSlash:
+			p.next()
+			ax = axisChild
+		default:
+			return steps, nil
+		}
+	}
+
+	return steps, nil
+}
+
+func stepStarts(k tokKind) bool {
+	return k == tokName || k == tokStar || k == tokAt
+}
+
+func (p *tokenParser) parseStep(ax axis) (step, error) {
+	st := step{axis: ax}
+
+	if p.peek().kind == tokAt {
+		p.next()
+		st.axis = axisAttribute
+	}
+
+	switch p.peek().kind {
+	case tokStar:
+		p.next()
+		st.star = true
+	case tokName:
+		st.name = p.next().s
+	default:
+		return step{}, fmt.Errorf("expected a node test")
+	}
+
+	for p.peek().kind == tokLBracket {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return step{}, err
+		}
+		if _, err := p.expect(tokRBracket); err != nil {
+			return step{}, err
+		}
+		st.preds = append(st.preds, e)
+	}
+
+	return st, nil
+}
+
+// --- expressions ---
+
+// expr is a predicate/function-argument expression.
+type expr interface {
+	eval(ctx evalCtx) (value, error)
+}
+
+type evalCtx struct {
+	node      *html.Node
+	pos, size int
+}
+
+func (p *tokenParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binExpr{op: "or", l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *tokenParser) parseAnd() (expr, error) {
+	left, err := p.parseCmp()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseCmp()
+		if err != nil {
+			return nil, err
+		}
+		left = &binExpr{op: "and", l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *tokenParser) parseCmp() (expr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	ops := map[tokKind]string{
+		tokEq: "=", tokNe: "!=", tokLt: "<", tokLe: "<=", tokGt: ">", tokGe: ">=",
+	}
+	if op, ok := ops[p.peek().kind]; ok {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &binExpr{op: op, l: left, r: right}, nil
+	}
+	return left, nil
+}
+
+func (p *tokenParser) parsePrimary() (expr, error) {
+	switch t := p.peek(); t.kind {
+	case tokNumber:
+		p.next()
+		return &numLit{n: t.n}, nil
+
+	case tokString:
+		p.next()
+		return &strLit{s: t.s}, nil
+
+	case tokName:
+		// Either a function call (name immediately followed by '(')
+		// or the start of a relative path (node test or axis).
+		if p.pos+1 < len(p.toks) && p.toks[p.pos+1].kind == tokLParen {
+			return p.parseFuncCall()
+		}
+		return p.parseRelativePath()
+
+	case tokAt, tokStar, tokSlash, tokSlashSlash:
+		return p.parseRelativePath()
+
+	default:
+		return nil, fmt.Errorf("unexpected token in expression")
+	}
+}
+
+func (p *tokenParser) parseFuncCall() (expr, error) {
+	name := p.next().s
+	if _, err := p.expect(tokLParen); err != nil {
+		return nil, err
+	}
+	var args []expr
+	if p.peek().kind != tokRParen {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	return &funcCall{name: strings.ToLower(name), args: args}, nil
+}
+
+func (p *tokenParser) parseRelativePath() (expr, error) {
+	steps, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("expected a path expression")
+	}
+	return &pathExpr{steps: steps}, nil
+}
+
+// --- expression node types ---
+
+type numLit struct{ n float64 }
+
+func (e *numLit) eval(evalCtx) (value, error) { return value{kind: valNumber, num: e.n}, nil }
+
+type strLit struct{ s string }
+
+func (e *strLit) eval(evalCtx) (value, error) { return value{kind: valString, str: e.s}, nil }
+
+type pathExpr struct{ steps []step }
+
+func (e *pathExpr) eval(ctx evalCtx) (value, error) {
+	nodes, err := (&path{steps: e.steps}).eval([]*html.Node{ctx.node})
+	if err != nil {
+		return value{}, err
+	}
+	return value{kind: valNodeSet, nodes: nodes}, nil
+}
+
+type binExpr struct {
+	op   string
+	l, r expr
+}
+
+func (e *binExpr) eval(ctx evalCtx) (value, error) {
+	lv, err := e.l.eval(ctx)
+	if err != nil {
+		return value{}, err
+	}
+	switch e.op {
+	case "and":
+		if !toBool(lv) {
+			return value{kind: valBool, boolean: false}, nil
+		}
+		rv, err := e.r.eval(ctx)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valBool, boolean: toBool(rv)}, nil
+	case "or":
+		if toBool(lv) {
+			return value{kind: valBool, boolean: true}, nil
+		}
+		rv, err := e.r.eval(ctx)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valBool, boolean: toBool(rv)}, nil
+	}
+
+	rv, err := e.r.eval(ctx)
+	if err != nil {
+		return value{}, err
+	}
+	return value{kind: valBool, boolean: compare(e.op, lv, rv)}, nil
+}
+
+func compare(op string, l, r value) bool {
+	if l.kind == valNumber || r.kind == valNumber {
+		a, b := toNumber(l), toNumber(r)
+		switch op {
+		case "=":
+			return a == b
+		case "!=":
+			return a != b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		}
+		return false
+	}
+	a, b := toString(l), toString(r)
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+type funcCall struct {
+	name string
+	args []expr
+}
+
+func (e *funcCall) eval(ctx evalCtx) (value, error) {
+	switch e.name {
+	case "position":
+		return value{kind: valNumber, num: float64(ctx.pos)}, nil
+
+	case "last":
+		return value{kind: valNumber, num: float64(ctx.size)}, nil
+
+	case "text":
+		s, err := htree.Text(ctx.node)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valString, str: s}, nil
+
+	case "name", "local-name":
+		n := ctx.node
+		if len(e.args) == 1 {
+			v, err := e.args[0].eval(ctx)
+			if err != nil {
+				return value{}, err
+			}
+			if len(v.nodes) == 0 {
+				return value{kind: valString, str: ""}, nil
+			}
+			n = v.nodes[0]
+		}
+		return value{kind: valString, str: n.Data}, nil
+
+	case "normalize-space":
+		s, err := e.stringArg(ctx, 0)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valString, str: strings.Join(strings.Fields(s), " ")}, nil
+
+	case "contains":
+		a, err := e.stringArg(ctx, 0)
+		if err != nil {
+			return value{}, err
+		}
+		b, err := e.stringArg(ctx, 1)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valBool, boolean: strings.Contains(a, b)}, nil
+
+	case "starts-with":
+		a, err := e.stringArg(ctx, 0)
+		if err != nil {
+			return value{}, err
+		}
+		b, err := e.stringArg(ctx, 1)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valBool, boolean: strings.HasPrefix(a, b)}, nil
+
+	case "count":
+		if len(e.args) != 1 {
+			return value{}, fmt.Errorf("count() takes exactly one argument")
+		}
+		v, err := e.args[0].eval(ctx)
+		if err != nil {
+			return value{}, err
+		}
+		return value{kind: valNumber, num: float64(len(v.nodes))}, nil
+
+	default:
+		return value{}, fmt.Errorf("unsupported function %q", e.name)
+	}
+}
+
+// stringArg evaluates the i'th argument (or, if absent, the context
+// node) and converts it to a string.
+func (e *funcCall) stringArg(ctx evalCtx, i int) (string, error) {
+	if i >= len(e.args) {
+		s, err := htree.Text(ctx.node)
+		return s, err
+	}
+	v, err := e.args[i].eval(ctx)
+	if err != nil {
+		return "", err
+	}
+	return toString(v), nil
+}
+
+// --- values ---
+
+type valueKind int
+
+const (
+	valNodeSet valueKind = iota
+	valString
+	valNumber
+	valBool
+)
+
+type value struct {
+	kind    valueKind
+	nodes   []*html.Node
+	str     string
+	num     float64
+	boolean bool
+}
+
+func toBool(v value) bool {
+	switch v.kind {
+	case valNodeSet:
+		return len(v.nodes) > 0
+	case valString:
+		return v.str != ""
+	case valNumber:
+		return v.num != 0
+	case valBool:
+		return v.boolean
+	}
+	return false
+}
+
+func toString(v value) string {
+	switch v.kind {
+	case valNodeSet:
+		if len(v.nodes) == 0 {
+			return ""
+		}
+		s, err := htree.Text(v.nodes[0])
+		if err != nil {
+			return ""
+		}
+		return s
+	case valString:
+		return v.str
+	case valNumber:
+		return strconv.FormatFloat(v.num, 'g', -1, 64)
+	case valBool:
+		if v.boolean {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+func toNumber(v value) float64 {
+	switch v.kind {
+	case valNumber:
+		return v.num
+	case valBool:
+		if v.boolean {
+			return 1
+		}
+		return 0
+	default:
+		n, err := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+		if err != nil {
+			return nan()
+		}
+		return n
+	}
+}
+
+func nan() float64 {
+	var zero float64
+	return zero / zero
+}
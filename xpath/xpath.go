@@ -0,0 +1,182 @@
+// Package xpath evaluates a practical subset of XPath 1.0
+// over trees of html.Nodes.
+//
+// Supported syntax is child and descendant-or-self steps
+// (`/` and `//`), the attribute axis (`@name`), wildcard
+// node tests (`*`), and bracketed predicates containing
+// position numbers, comparisons, `and`/`or`, and the
+// functions text(), contains(), starts-with(),
+// normalize-space(), name(), local-name(), count(), last(),
+// and position().
+//
+// Since html.Node has no dedicated attribute-node type,
+// results of the attribute axis are represented as synthetic
+// text nodes (Type [html.TextNode]) holding the attribute's
+// value, with Parent set to the element that owns it.
+package xpath
+
+import (
+	"fmt"
+
+	"github.com/bobg/htree"
+	"golang.org/x/net/html"
+)
+
+// Eval evaluates the XPath expression expr against root
+// and returns the matching nodes in the order produced by the query plan.
+func Eval(root *html.Node, expr string) ([]*html.Node, error) {
+	path, err := compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compiling xpath expression %q: %w", expr, err)
+	}
+	return path.eval([]*html.Node{root})
+}
+
+// EvalSeq is like [Eval] but produces a [htree.Seq]
+// instead of a slice.
+func EvalSeq(root *html.Node, expr string) (htree.Seq, error) {
+	nodes, err := Eval(root, expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(*html.Node) bool) {
+		for _, n := range nodes {
+			if !yield(n) {
+				return
+			}
+		}
+	}, nil
+}
+
+// axis identifies the relationship a step's node test
+// bears to its context node.
+type axis int
+
+const (
+	axisChild axis = iota
+	axisDescendantOrSelf
+	axisAttribute
+)
+
+// step is one segment of a compiled path: an axis, a node test,
+// and the predicates that further filter its results.
+type step struct {
+	axis  axis
+	name  string // element or attribute name to match; unused when star is set
+	star  bool
+	preds []expr
+}
+
+// path is a compiled sequence of steps.
+type path struct {
+	steps []step
+}
+
+func (p *path) eval(context []*html.Node) ([]*html.Node, error) {
+	nodes := context
+	for _, st := range p.steps {
+		var err error
+		nodes, err = evalStep(st, nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// evalStep applies a single step to every node in context.
+// Predicates see position()/last() scoped to the matches produced by
+// their own context node, as XPath requires, so each context node's
+// matches are filtered separately before being concatenated.
+func evalStep(st step, context []*html.Node) ([]*html.Node, error) {
+	var result []*html.Node
+	for _, ctx := range context {
+		filtered, err := filterPredicates(stepAxis(st, ctx), st.preds)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, filtered...)
+	}
+	return result, nil
+}
+
+func stepAxis(st step, ctx *html.Node) []*html.Node {
+	switch st.axis {
+	case axisAttribute:
+		return attrNodes(ctx, st.name, st.star)
+
+	case axisDescendantOrSelf:
+		var out []*html.Node
+		for n := range htree.Walk(ctx) {
+			if nodeTestMatch(st, n) {
+				out = append(out, n)
+			}
+		}
+		return out
+
+	default: // axisChild
+		var out []*html.Node
+		for c := ctx.FirstChild; c != nil; c = c.NextSibling {
+			if nodeTestMatch(st, c) {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+}
+
+func nodeTestMatch(st step, n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	return st.star || n.Data == st.name
+}
+
+// attrNodes returns synthetic text nodes for the attributes of n
+// that match name (or all of them, if star is set).
+func attrNodes(n *html.Node, name string, star bool) []*html.Node {
+	if n.Type != html.ElementNode {
+		return nil
+	}
+	var out []*html.Node
+	for _, a := range n.Attr {
+		if !star && a.Key != name {
+			continue
+		}
+		out = append(out, &html.Node{
+			Type:   html.TextNode,
+			Data:   a.Val,
+			Parent: n,
+		})
+	}
+	return out
+}
+
+func filterPredicates(nodes []*html.Node, preds []expr) ([]*html.Node, error) {
+	for _, p := range preds {
+		var kept []*html.Node
+		size := len(nodes)
+		for i, n := range nodes {
+			ctx := evalCtx{node: n, pos: i + 1, size: size}
+			v, err := p.eval(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if predicateHolds(v, ctx) {
+				kept = append(kept, n)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+// predicateHolds applies XPath's implicit-position rule:
+// a bare number N in a predicate means position() = N,
+// anything else is converted to boolean.
+func predicateHolds(v value, ctx evalCtx) bool {
+	if v.kind == valNumber {
+		return float64(ctx.pos) == v.num
+	}
+	return toBool(v)
+}
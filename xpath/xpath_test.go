@@ -0,0 +1,129 @@
+package xpath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bobg/htree"
+	"golang.org/x/net/html"
+)
+
+func TestEval(t *testing.T) {
+	const doc = `
+<html><body>
+  <ul id="list">
+    <li class="a">one</li>
+    <li class="b">two</li>
+    <li class="b">three</li>
+  </ul>
+  <p title="greeting">hello</p>
+</body></html>
+`
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		expr string
+		want []string
+	}{
+		{"//li", []string{"one", "two", "three"}},
+		{"//li[@class='b']", []string{"two", "three"}},
+		{"//li[2]", []string{"two"}},
+		{"//li[last()]", []string{"three"}},
+		{"//ul/li", []string{"one", "two", "three"}},
+		{"//p[contains(text(), 'hell')]", []string{"hello"}},
+		{"//p[starts-with(@title, 'greet')]", []string{"hello"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			nodes, err := Eval(root, c.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range nodes {
+				s, err := htree.Text(n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, s)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+			for i := range c.want {
+				if got[i] != c.want[i] {
+					t.Errorf("got %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvalAttribute(t *testing.T) {
+	const doc = `<a href="https://example.com">link</a>`
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := Eval(root, "//a/@href")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("got %d nodes, want 1", len(nodes))
+	}
+	if nodes[0].Data != "https://example.com" {
+		t.Errorf("got %q, want %q", nodes[0].Data, "https://example.com")
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	if _, err := Eval(nil, "//li["); err == nil {
+		t.Error("expected an error for malformed expression")
+	}
+}
+
+// TestEvalPositionPerParent verifies that a positional predicate is
+// scoped to each context node's own matches, not to every match of
+// the step pooled across all context nodes.
+func TestEvalPositionPerParent(t *testing.T) {
+	const doc = `
+<table>
+  <tr><td>r1c1</td><td>r1c2</td></tr>
+  <tr><td>r2c1</td><td>r2c2</td></tr>
+</table>
+`
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := Eval(root, "//tr/td[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, n := range nodes {
+		s, err := htree.Text(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+
+	want := []string{"r1c1", "r2c1"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}